@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"expvar"
 	"flag"
 	"fmt"
@@ -11,23 +12,19 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/dgraph-io/badger"
 	"github.com/profefe/profefe/pkg/config"
 	"github.com/profefe/profefe/pkg/log"
 	"github.com/profefe/profefe/pkg/middleware"
 	"github.com/profefe/profefe/pkg/profefe"
-	"github.com/profefe/profefe/pkg/storage"
-	storageBadger "github.com/profefe/profefe/pkg/storage/badger"
-	storageS3 "github.com/profefe/profefe/pkg/storage/s3"
+	"github.com/profefe/profefe/pkg/storage/factory"
+	// Storage drivers register themselves with pkg/storage/factory on import.
+	_ "github.com/profefe/profefe/pkg/storage/badger"
+	_ "github.com/profefe/profefe/pkg/storage/blob"
+	_ "github.com/profefe/profefe/pkg/storage/s3"
 	"github.com/profefe/profefe/version"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.uber.org/zap"
 	"golang.org/x/xerrors"
 )
 
@@ -49,42 +46,41 @@ func main() {
 		panic(err)
 	}
 
-	if err := run(logger, conf, os.Stdout); err != nil {
-		logger.Error(err)
+	if err := run(logger, conf); err != nil {
+		logger.Error("profefe exited with error", "error", err)
 	}
 }
 
-func run(logger *log.Logger, conf config.Config, stdout io.Writer) error {
-	var (
-		sr storage.Reader
-		sw storage.Writer
-	)
-	if conf.Badger.Dir != "" {
-		st, closer, err := initBadgerStorage(logger, conf)
-		if err != nil {
-			return err
-		}
-		defer closer.Close()
-		sr, sw = st, st
-	} else if conf.S3.Bucket != "" {
-		st, err := initS3Storage(logger, conf)
-		if err != nil {
-			return err
-		}
-		sr, sw = st, st
-	} else {
-		return fmt.Errorf("storage configuration required")
+func run(logger *log.Logger, conf config.Config) error {
+	if conf.Storage.Driver == "" {
+		return fmt.Errorf("storage configuration required: -storage.driver must be set")
+	}
+
+	// storageCtx governs background work storage drivers start (e.g.
+	// badger's value-log GC loop); it's canceled as soon as we start
+	// shutting down, ahead of the storage driver's own Close.
+	storageCtx, cancelStorage := context.WithCancel(context.Background())
+	defer cancelStorage()
+
+	st, err := factory.Create(storageCtx, conf.Storage.Driver, logger, prometheus.DefaultRegisterer, conf.Storage.Params)
+	if err != nil {
+		return xerrors.Errorf("could not init storage driver %q: %w", conf.Storage.Driver, err)
+	}
+	if closer, ok := st.(io.Closer); ok {
+		defer func() {
+			cancelStorage()
+			closer.Close()
+		}()
 	}
 
 	mux := http.NewServeMux()
 
-	profefe.SetupRoutes(mux, logger, prometheus.DefaultRegisterer, sr, sw)
+	profefe.SetupRoutes(mux, prometheus.DefaultRegisterer, st, st)
 
-	setupDebugRoutes(mux)
+	setupDebugRoutes(mux, st)
 
-	// TODO(narqo) hardcoded stdout when setup logging middleware
-	h := middleware.LoggingHandler(stdout, mux)
-	h = middleware.RecoveryHandler(h)
+	h := middleware.RecoveryHandler(mux)
+	h = middleware.LoggingHandler(logger, h)
 
 	server := http.Server{
 		Addr:    conf.Addr,
@@ -93,7 +89,7 @@ func run(logger *log.Logger, conf config.Config, stdout io.Writer) error {
 
 	errc := make(chan error, 1)
 	go func() {
-		logger.Infow("server is running", "addr", server.Addr)
+		logger.Info("server is running", "addr", server.Addr)
 		errc <- server.ListenAndServe()
 	}()
 
@@ -109,58 +105,21 @@ func run(logger *log.Logger, conf config.Config, stdout io.Writer) error {
 		}
 	}
 
+	cancelStorage()
+
 	ctx, cancel := context.WithTimeout(context.Background(), conf.ExitTimeout)
 	defer cancel()
 
 	return server.Shutdown(ctx)
 }
 
-func initBadgerStorage(logger *log.Logger, conf config.Config) (*storageBadger.Storage, io.Closer, error) {
-	opt := badger.DefaultOptions(conf.Badger.Dir)
-	db, err := badger.Open(opt)
-	if err != nil {
-		return nil, nil, xerrors.Errorf("could not open db: %w", err)
-	}
-
-	// run values garbage collection, see https://github.com/dgraph-io/badger#garbage-collection
-	go func() {
-		for {
-			err := db.RunValueLogGC(conf.Badger.GCDiscardRatio)
-			if err == nil {
-				// nil error is not the expected behaviour, because
-				// badger returns ErrNoRewrite as an indicator that everything went ok
-				continue
-			} else if err != badger.ErrNoRewrite {
-				logger.Errorw("badger failed to run value log garbage collection", zap.Error(err))
-			}
-			time.Sleep(conf.Badger.GCInterval)
-		}
-	}()
-
-	st := storageBadger.New(logger, db, conf.Badger.ProfileTTL)
-	return st, db, nil
-}
-
-func initS3Storage(logger *log.Logger, conf config.Config) (*storageS3.Storage, error) {
-	var forcePathStyle bool
-	if conf.S3.EndpointURL != "" {
-		// should one use custom object storage service (e.g. Minio), path-style addressing needs to be set
-		forcePathStyle = true
-	}
-	sess, err := session.NewSession(&aws.Config{
-		Endpoint:         aws.String(conf.S3.EndpointURL),
-		DisableSSL:       aws.Bool(conf.S3.DisableSSL),
-		Region:           aws.String(conf.S3.Region),
-		MaxRetries:       aws.Int(conf.S3.MaxRetries),
-		S3ForcePathStyle: aws.Bool(forcePathStyle),
-	})
-	if err != nil {
-		return nil, xerrors.Errorf("could not create s3 session: %w", err)
-	}
-	return storageS3.New(logger, s3.New(sess), conf.S3.Bucket), nil
+// gcRunner is implemented by storage drivers (currently only badger) that
+// support an on-demand garbage collection pass.
+type gcRunner interface {
+	RunGC(ctx context.Context) (rewritten bool, err error)
 }
 
-func setupDebugRoutes(mux *http.ServeMux) {
+func setupDebugRoutes(mux *http.ServeMux, st interface{}) {
 	// pprof handlers, see https://github.com/golang/go/blob/release-branch.go1.13/src/net/http/pprof/pprof.go
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
@@ -176,4 +135,30 @@ func setupDebugRoutes(mux *http.ServeMux) {
 
 	// prometheus handlers
 	mux.Handle("/debug/metrics", promhttp.Handler())
+
+	// storage driver discovery, backed by pkg/storage/factory
+	mux.HandleFunc("/debug/storage/drivers", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(factory.Drivers())
+	})
+
+	// on-demand garbage collection, currently only supported by the badger
+	// storage driver
+	if gc, ok := st.(gcRunner); ok {
+		mux.HandleFunc("/debug/storage/badger/gc", func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+
+			rewritten, err := gc.RunGC(req.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]bool{"rewritten": rewritten})
+		})
+	}
 }
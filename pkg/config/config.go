@@ -0,0 +1,71 @@
+// Package config defines profefe's runtime configuration and the flags that
+// populate it.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/profefe/profefe/pkg/log"
+)
+
+// Config is the top-level configuration for the profefe server.
+type Config struct {
+	Addr        string
+	ExitTimeout time.Duration
+
+	Logger  log.Config
+	Storage StorageConfig
+}
+
+// StorageConfig selects a storage driver, registered in
+// pkg/storage/factory, and carries the generic parameters it is configured
+// with (e.g. "dir" for the badger driver, "bucket" for the s3 driver).
+type StorageConfig struct {
+	Driver string
+	Params map[string]interface{}
+}
+
+// RegisterFlags registers every flag contributing to Config with f.
+func (c *Config) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&c.Addr, "addr", ":10100", "address to listen on")
+	f.DurationVar(&c.ExitTimeout, "exit-timeout", 5*time.Second, "timeout for graceful shutdown")
+
+	c.Logger.RegisterFlags(f)
+	c.Storage.RegisterFlags(f)
+}
+
+// RegisterFlags registers the storage driver flags with f.
+func (c *StorageConfig) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&c.Driver, "storage.driver", "", "name of the storage driver to use, see /debug/storage/drivers for the set available in this build (e.g. badger, s3, blob)")
+	f.Var(&storageParamsValue{&c.Params}, "storage.param", "storage driver parameter in key=value form; may be repeated")
+}
+
+// storageParamsValue is a flag.Value that accumulates repeated key=value
+// flags into a map[string]interface{}.
+type storageParamsValue struct {
+	params *map[string]interface{}
+}
+
+func (v *storageParamsValue) String() string {
+	if v.params == nil {
+		return ""
+	}
+	return fmt.Sprint(*v.params)
+}
+
+func (v *storageParamsValue) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("storage.param %q: expected key=value", s)
+	}
+
+	if *v.params == nil {
+		*v.params = make(map[string]interface{})
+	}
+	(*v.params)[parts[0]] = parts[1]
+
+	return nil
+}
@@ -0,0 +1,80 @@
+// Package log provides the logger used throughout profefe. It is a thin
+// adapter over the standard library's log/slog, so call sites get
+// structured, leveled logging with either a JSON or logfmt-style handler.
+package log
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Logger is profefe's logger. The zero value is not usable; construct one
+// via Config.Build.
+type Logger struct {
+	*slog.Logger
+}
+
+// With returns a Logger that includes the given attributes on every
+// subsequent log line.
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{l.Logger.With(args...)}
+}
+
+// Config holds the flags that control how a Logger is built.
+type Config struct {
+	Level  string
+	Format string
+}
+
+// RegisterFlags registers the logger's flags with f.
+func (c *Config) RegisterFlags(f *flag.FlagSet) {
+	f.StringVar(&c.Level, "log.level", "info", "log level, one of: debug, info, warn, error")
+	f.StringVar(&c.Format, "log.format", "logfmt", "log output format, one of: json, logfmt")
+}
+
+// Build constructs a Logger from the config, writing to stdout.
+func (c Config) Build() (*Logger, error) {
+	return c.build(os.Stdout)
+}
+
+func (c Config) build(w io.Writer) (*Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return nil, fmt.Errorf("log: invalid level %q: %w", c.Level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch c.Format {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "logfmt", "":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("log: unknown format %q, want one of: json, logfmt", c.Format)
+	}
+
+	return &Logger{slog.New(handler)}, nil
+}
+
+type ctxKey struct{}
+
+// ContextWithLogger returns a copy of ctx carrying logger, retrievable via
+// LoggerFromContext.
+func ContextWithLogger(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// LoggerFromContext returns the Logger stored in ctx by
+// middleware.LoggingHandler, or slog's default logger if ctx carries none.
+func LoggerFromContext(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+		return logger
+	}
+	return &Logger{slog.Default()}
+}
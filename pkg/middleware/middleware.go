@@ -0,0 +1,77 @@
+// Package middleware provides HTTP middleware shared by profefe's server.
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/profefe/profefe/pkg/log"
+)
+
+var requestSeq uint64
+
+func nextRequestID() string {
+	return strconv.FormatUint(atomic.AddUint64(&requestSeq, 1), 36)
+}
+
+// LoggingHandler wraps next, logging one line per request to logger and
+// attaching a per-request Logger (tagged with a request-id) to the request
+// context, retrievable via log.LoggerFromContext.
+func LoggingHandler(logger *log.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		reqID := nextRequestID()
+
+		reqLogger := logger.With("request_id", reqID)
+		req = req.WithContext(log.ContextWithLogger(req.Context(), reqLogger))
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, req)
+
+		reqLogger.Info(
+			"handled request",
+			"method", req.Method,
+			"path", req.URL.Path,
+			"status", sw.status,
+			"duration", time.Since(start),
+			"bytes", sw.bytes,
+		)
+	})
+}
+
+// RecoveryHandler recovers from panics in next, logging the panic via the
+// per-request Logger attached to the request context (see LoggingHandler)
+// and returning a 500 response instead of crashing the server. It must run
+// inside LoggingHandler, so the panic is recovered before it unwinds past
+// LoggingHandler, whose access-log line would otherwise be skipped.
+func RecoveryHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.LoggerFromContext(req.Context()).Error("panic while handling request", "panic", rec, "stack", string(debug.Stack()))
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(w, req)
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	sw.status = status
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	n, err := sw.ResponseWriter.Write(p)
+	sw.bytes += n
+	return n, err
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/profefe/profefe/pkg/log"
+)
+
+func newTestLogger(buf *bytes.Buffer) *log.Logger {
+	return &log.Logger{Logger: slog.New(slog.NewTextHandler(buf, nil))}
+}
+
+func TestLoggingHandlerLogsRequest(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	h := LoggingHandler(logger, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/foo", nil)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := buf.String()
+	if !strings.Contains(line, "handled request") {
+		t.Fatalf("expected access log line, got %q", line)
+	}
+	if !strings.Contains(line, "request_id=") {
+		t.Fatalf("expected request_id attribute, got %q", line)
+	}
+	if !strings.Contains(line, "status=418") {
+		t.Fatalf("expected status=418, got %q", line)
+	}
+}
+
+func TestRecoveryHandlerLogsPanicUnderLoggingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	h := RecoveryHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	}))
+	h = LoggingHandler(logger, h)
+
+	req := httptest.NewRequest(http.MethodGet, "/panics", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "panic while handling request") {
+		t.Fatalf("expected panic log line, got %q", out)
+	}
+	if !strings.Contains(out, "handled request") {
+		t.Fatalf("expected the request to still be access-logged after recovering, got %q", out)
+	}
+
+	panicLine, accessLine := "", ""
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if strings.Contains(line, "panic while handling request") {
+			panicLine = line
+		}
+		if strings.Contains(line, "handled request") {
+			accessLine = line
+		}
+	}
+
+	if !strings.Contains(panicLine, "request_id=") {
+		t.Fatalf("panic log line is missing request_id, got %q", panicLine)
+	}
+	if !strings.Contains(accessLine, "status=500") {
+		t.Fatalf("access log line should reflect the 500 written by RecoveryHandler, got %q", accessLine)
+	}
+}
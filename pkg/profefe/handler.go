@@ -0,0 +1,86 @@
+package profefe
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/profefe/profefe/pkg/log"
+	"github.com/profefe/profefe/pkg/storage"
+)
+
+func (h *handler) writeProfile(w http.ResponseWriter, req *http.Request) {
+	service := req.URL.Query().Get("service")
+	if service == "" {
+		http.Error(w, "service is required", http.StatusBadRequest)
+		return
+	}
+
+	meta := storage.Meta{
+		ProfileID: storage.ProfileID(strconv.FormatInt(time.Now().UnixNano(), 36)),
+		Service:   service,
+		Type:      req.URL.Query().Get("type"),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := h.sw.WriteProfile(req.Context(), meta, req.Body); err != nil {
+		log.LoggerFromContext(req.Context()).Error("failed to write profile", "service", service, "error", err)
+		http.Error(w, "failed to write profile", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(meta)
+}
+
+func (h *handler) listProfiles(w http.ResponseWriter, req *http.Request) {
+	service := req.URL.Query().Get("service")
+	if service == "" {
+		http.Error(w, "service is required", http.StatusBadRequest)
+		return
+	}
+
+	profiles, err := h.sr.ListProfiles(req.Context(), service)
+	if err != nil {
+		log.LoggerFromContext(req.Context()).Error("failed to list profiles", "service", service, "error", err)
+		http.Error(w, "failed to list profiles", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(profiles)
+}
+
+func (h *handler) readProfile(w http.ResponseWriter, req *http.Request) {
+	service := req.URL.Query().Get("service")
+	if service == "" {
+		http.Error(w, "service is required", http.StatusBadRequest)
+		return
+	}
+
+	id := storage.ProfileID(req.URL.Path[len("/api/0/profiles/"):])
+	if id == "" {
+		http.Error(w, "profile id is required", http.StatusBadRequest)
+		return
+	}
+
+	rc, err := h.sr.ReadProfile(req.Context(), service, id)
+	if err != nil {
+		log.LoggerFromContext(req.Context()).Error("failed to read profile", "profile_id", id, "error", err)
+		http.Error(w, "failed to read profile", http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := rc.Read(buf)
+		if n > 0 {
+			w.Write(buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
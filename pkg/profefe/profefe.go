@@ -0,0 +1,41 @@
+// Package profefe implements the HTTP API profefe exposes for collecting
+// and querying profiling data.
+package profefe
+
+import (
+	"net/http"
+
+	"github.com/profefe/profefe/pkg/storage"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SetupRoutes registers profefe's API handlers on mux.
+func SetupRoutes(mux *http.ServeMux, reg prometheus.Registerer, sr storage.Reader, sw storage.Writer) {
+	h := &handler{
+		sr: sr,
+		sw: sw,
+	}
+
+	mux.HandleFunc("/api/0/profiles", h.handleProfiles)
+	mux.HandleFunc("/api/0/profiles/", h.handleProfile)
+}
+
+type handler struct {
+	sr storage.Reader
+	sw storage.Writer
+}
+
+func (h *handler) handleProfiles(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPost:
+		h.writeProfile(w, req)
+	case http.MethodGet:
+		h.listProfiles(w, req)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *handler) handleProfile(w http.ResponseWriter, req *http.Request) {
+	h.readProfile(w, req)
+}
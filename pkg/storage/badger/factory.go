@@ -0,0 +1,51 @@
+package badger
+
+import (
+	"context"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"github.com/profefe/profefe/pkg/log"
+	"github.com/profefe/profefe/pkg/storage"
+	"github.com/profefe/profefe/pkg/storage/factory"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	factory.Register("badger", &driverFactory{})
+}
+
+type driverFactory struct{}
+
+func (f *driverFactory) Create(ctx context.Context, logger *log.Logger, reg prometheus.Registerer, parameters map[string]interface{}) (storage.ReadWriter, error) {
+	params := factory.Params(parameters)
+
+	dir, err := params.RequireString("dir")
+	if err != nil {
+		return nil, err
+	}
+	profileTTL, err := params.Duration("profile_ttl", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	gcInterval, err := params.Duration("gc_interval", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	gcDiscardRatio, err := params.Float64("gc_discard_ratio", 0.5)
+	if err != nil {
+		return nil, err
+	}
+
+	opt := badger.DefaultOptions(dir)
+	db, err := badger.Open(opt)
+	if err != nil {
+		return nil, xerrors.Errorf("could not open db: %w", err)
+	}
+
+	st := New(logger, db, profileTTL, gcDiscardRatio, reg)
+	st.StartGCLoop(ctx, gcInterval)
+
+	return st, nil
+}
@@ -0,0 +1,215 @@
+// Package badger implements storage.Reader and storage.Writer on top of an
+// embedded badger key-value database.
+package badger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"github.com/profefe/profefe/pkg/log"
+	"github.com/profefe/profefe/pkg/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/xerrors"
+)
+
+// Storage is a storage.Reader/storage.Writer backed by badger.
+type Storage struct {
+	logger     *log.Logger
+	db         *badger.DB
+	profileTTL time.Duration
+
+	gcDiscardRatio float64
+
+	gcRunsTotal     prometheus.Counter
+	gcRewritesTotal prometheus.Counter
+	gcLastDuration  prometheus.Gauge
+
+	gcDone chan struct{}
+}
+
+// New returns a new Storage. profileTTL, if non-zero, is used to expire
+// stored profiles via badger's own TTL support. gcDiscardRatio is the
+// discard ratio passed to every RunGC call, see
+// https://github.com/dgraph-io/badger#garbage-collection.
+func New(logger *log.Logger, db *badger.DB, profileTTL time.Duration, gcDiscardRatio float64, reg prometheus.Registerer) *Storage {
+	st := &Storage{
+		logger:         logger,
+		db:             db,
+		profileTTL:     profileTTL,
+		gcDiscardRatio: gcDiscardRatio,
+		gcRunsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "badger_gc_runs_total",
+			Help: "Total number of badger value-log garbage collection runs.",
+		}),
+		gcRewritesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "badger_gc_rewrites_total",
+			Help: "Total number of badger value-log garbage collection runs that rewrote a value log file.",
+		}),
+		gcLastDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "badger_gc_last_run_duration_seconds",
+			Help: "Duration, in seconds, of the last badger value-log garbage collection run.",
+		}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(st.gcRunsTotal, st.gcRewritesTotal, st.gcLastDuration)
+	}
+
+	return st
+}
+
+// Close closes the underlying badger database. If StartGCLoop was called,
+// Close blocks until its goroutine has observed ctx's cancellation and
+// returned, so a GC pass can never run concurrently with the database
+// closing underneath it.
+func (st *Storage) Close() error {
+	if st.gcDone != nil {
+		<-st.gcDone
+	}
+	return st.db.Close()
+}
+
+// StartGCLoop runs RunGCLoop(ctx, interval) in its own goroutine. Close
+// waits for that goroutine to exit before closing the database.
+func (st *Storage) StartGCLoop(ctx context.Context, interval time.Duration) {
+	st.gcDone = make(chan struct{})
+	go func() {
+		defer close(st.gcDone)
+		st.RunGCLoop(ctx, interval)
+	}()
+}
+
+// RunGC runs a single pass of badger's value-log garbage collection and
+// reports whether it rewrote a value log file.
+func (st *Storage) RunGC(ctx context.Context) (rewritten bool, err error) {
+	start := time.Now()
+	err = st.db.RunValueLogGC(st.gcDiscardRatio)
+	st.gcRunsTotal.Inc()
+	st.gcLastDuration.Set(time.Since(start).Seconds())
+
+	if err == nil {
+		st.gcRewritesTotal.Inc()
+		return true, nil
+	} else if err == badger.ErrNoRewrite {
+		// ErrNoRewrite is badger's way of saying everything went ok, but
+		// there was nothing to reclaim.
+		return false, nil
+	}
+	return false, xerrors.Errorf("could not run value log garbage collection: %w", err)
+}
+
+// RunGCLoop runs RunGC in a loop until ctx is canceled, see
+// https://github.com/dgraph-io/badger#garbage-collection. A successful
+// rewrite is retried immediately, since there may be more to reclaim;
+// otherwise RunGCLoop waits interval before trying again.
+func (st *Storage) RunGCLoop(ctx context.Context, interval time.Duration) {
+	for {
+		rewritten, err := st.RunGC(ctx)
+		if err != nil {
+			st.logger.Error("badger failed to run value log garbage collection", "error", err)
+		}
+
+		if rewritten {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+type record struct {
+	Meta    storage.Meta
+	Payload []byte
+}
+
+func (st *Storage) WriteProfile(ctx context.Context, meta storage.Meta, r io.Reader) error {
+	payload, err := ioutil.ReadAll(r)
+	if err != nil {
+		return xerrors.Errorf("could not read profile payload: %w", err)
+	}
+
+	data, err := json.Marshal(record{Meta: meta, Payload: payload})
+	if err != nil {
+		return xerrors.Errorf("could not marshal profile record: %w", err)
+	}
+
+	return st.db.Update(func(txn *badger.Txn) error {
+		entry := badger.NewEntry([]byte(meta.Key()), data)
+		if st.profileTTL > 0 {
+			entry = entry.WithTTL(st.profileTTL)
+		}
+		return txn.SetEntry(entry)
+	})
+}
+
+func (st *Storage) ReadProfile(ctx context.Context, service string, id storage.ProfileID) (io.ReadCloser, error) {
+	key := service + "/" + string(id)
+
+	var rec record
+	err := st.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &rec)
+		})
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, xerrors.Errorf("profile %s not found: %w", id, err)
+	} else if err != nil {
+		return nil, xerrors.Errorf("could not read profile %s: %w", id, err)
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(rec.Payload)), nil
+}
+
+func (st *Storage) ListProfiles(ctx context.Context, service string) ([]storage.Meta, error) {
+	prefix := []byte(service + "/")
+
+	var metas []storage.Meta
+	err := st.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			err := item.Value(func(val []byte) error {
+				var rec record
+				if err := json.Unmarshal(val, &rec); err != nil {
+					return err
+				}
+				metas = append(metas, rec.Meta)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("could not list profiles for service %s: %w", service, err)
+	}
+
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].CreatedAt.After(metas[j].CreatedAt)
+	})
+
+	return metas, nil
+}
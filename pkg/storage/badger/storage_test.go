@@ -0,0 +1,62 @@
+package badger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"github.com/profefe/profefe/pkg/log"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	dir := t.TempDir()
+	db, err := badger.Open(badger.DefaultOptions(dir))
+	if err != nil {
+		t.Fatalf("could not open badger db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	logger := log.LoggerFromContext(context.Background())
+	return New(logger, db, 0, 0.5, nil)
+}
+
+func TestCloseWaitsForGCLoop(t *testing.T) {
+	st := newTestStorage(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	st.StartGCLoop(ctx, time.Hour)
+
+	// Canceling ctx should let the GC goroutine exit almost immediately;
+	// Close must block until it has, rather than racing db.Close() against
+	// a RunGC call still in flight.
+	cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- st.Close() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return after the GC loop's context was canceled")
+	}
+
+	select {
+	case <-st.gcDone:
+	default:
+		t.Fatal("gcDone was not closed by the time Close returned")
+	}
+}
+
+func TestCloseWithoutGCLoop(t *testing.T) {
+	st := newTestStorage(t)
+
+	if err := st.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+}
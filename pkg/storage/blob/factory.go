@@ -0,0 +1,27 @@
+package blob
+
+import (
+	"context"
+
+	"github.com/profefe/profefe/pkg/log"
+	"github.com/profefe/profefe/pkg/storage"
+	"github.com/profefe/profefe/pkg/storage/factory"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	factory.Register("blob", &driverFactory{})
+}
+
+type driverFactory struct{}
+
+func (f *driverFactory) Create(ctx context.Context, logger *log.Logger, reg prometheus.Registerer, parameters map[string]interface{}) (storage.ReadWriter, error) {
+	params := factory.Params(parameters)
+
+	url, err := params.RequireString("url")
+	if err != nil {
+		return nil, err
+	}
+
+	return Open(ctx, url)
+}
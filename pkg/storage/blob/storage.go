@@ -0,0 +1,133 @@
+// Package blob implements storage.Reader and storage.Writer on top of
+// gocloud.dev/blob, giving profefe a single driver that supports GCS, Azure
+// Blob, local-filesystem, and in-memory storage without a bespoke driver per
+// backend. Other gocloud URL schemes (e.g. OpenStack Swift or B2) open only
+// once a package registering their URL opener is imported; this package
+// imports none beyond the four above.
+package blob
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/profefe/profefe/pkg/log"
+	"github.com/profefe/profefe/pkg/storage"
+	"gocloud.dev/blob"
+	"golang.org/x/xerrors"
+
+	// Register gocloud's URL openers for the schemes profefe supports out of
+	// the box: gs://, azblob://, file://, mem://.
+	_ "gocloud.dev/blob/azureblob"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/gcsblob"
+	_ "gocloud.dev/blob/memblob"
+)
+
+const metaKeySuffix = ".meta.json"
+
+// Storage is a storage.Reader/storage.Writer backed by a gocloud.dev/blob
+// bucket.
+type Storage struct {
+	bucket *blob.Bucket
+}
+
+// Open opens the bucket addressed by rawURL (e.g. "gs://bucket",
+// "azblob://container", "file:///var/lib/profefe", "mem://") and returns a
+// Storage backed by it.
+func Open(ctx context.Context, rawURL string) (*Storage, error) {
+	bucket, err := blob.OpenBucket(ctx, rawURL)
+	if err != nil {
+		return nil, xerrors.Errorf("could not open bucket %s: %w", rawURL, err)
+	}
+	return New(bucket), nil
+}
+
+// New returns a new Storage backed by an already-opened bucket.
+func New(bucket *blob.Bucket) *Storage {
+	return &Storage{
+		bucket: bucket,
+	}
+}
+
+// Close closes the underlying bucket.
+func (st *Storage) Close() error {
+	return st.bucket.Close()
+}
+
+func (st *Storage) WriteProfile(ctx context.Context, meta storage.Meta, r io.Reader) error {
+	key := meta.Key()
+
+	w, err := st.bucket.NewWriter(ctx, key, nil)
+	if err != nil {
+		return xerrors.Errorf("could not open writer for %s: %w", key, err)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return xerrors.Errorf("could not write profile %s: %w", key, err)
+	}
+	if err := w.Close(); err != nil {
+		return xerrors.Errorf("could not close writer for %s: %w", key, err)
+	}
+
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return xerrors.Errorf("could not marshal profile metadata: %w", err)
+	}
+	if err := st.bucket.WriteAll(ctx, key+metaKeySuffix, metaJSON, nil); err != nil {
+		return xerrors.Errorf("could not write profile metadata %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func (st *Storage) ReadProfile(ctx context.Context, service string, id storage.ProfileID) (io.ReadCloser, error) {
+	key := service + "/" + string(id)
+
+	r, err := st.bucket.NewReader(ctx, key, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("could not open reader for %s: %w", key, err)
+	}
+	return r, nil
+}
+
+func (st *Storage) ListProfiles(ctx context.Context, service string) ([]storage.Meta, error) {
+	logger := log.LoggerFromContext(ctx)
+	prefix := service + "/"
+
+	var metas []storage.Meta
+	iter := st.bucket.List(&blob.ListOptions{Prefix: prefix})
+	for {
+		obj, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("could not list profiles for service %s: %w", service, err)
+		}
+		if !strings.HasSuffix(obj.Key, metaKeySuffix) {
+			continue
+		}
+
+		data, err := st.bucket.ReadAll(ctx, obj.Key)
+		if err != nil {
+			logger.Error("could not read profile metadata", "key", obj.Key, "error", err)
+			continue
+		}
+
+		var meta storage.Meta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			logger.Error("could not unmarshal profile metadata", "key", obj.Key, "error", err)
+			continue
+		}
+		metas = append(metas, meta)
+	}
+
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].CreatedAt.After(metas[j].CreatedAt)
+	})
+
+	return metas, nil
+}
@@ -0,0 +1,81 @@
+package blob
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/profefe/profefe/pkg/storage"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	st, err := Open(context.Background(), "mem://")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+
+	return st
+}
+
+func TestWriteReadListProfiles(t *testing.T) {
+	st := newTestStorage(t)
+	ctx := context.Background()
+
+	metas := []storage.Meta{
+		{ProfileID: "1", Service: "svc", Type: "cpu", CreatedAt: time.Now().Add(-time.Minute)},
+		{ProfileID: "2", Service: "svc", Type: "cpu", CreatedAt: time.Now()},
+	}
+	payloads := map[storage.ProfileID]string{
+		"1": "first profile payload",
+		"2": "second profile payload",
+	}
+
+	for _, m := range metas {
+		if err := st.WriteProfile(ctx, m, strings.NewReader(payloads[m.ProfileID])); err != nil {
+			t.Fatalf("WriteProfile(%s): %v", m.ProfileID, err)
+		}
+	}
+
+	for id, want := range payloads {
+		rc, err := st.ReadProfile(ctx, "svc", id)
+		if err != nil {
+			t.Fatalf("ReadProfile(%s): %v", id, err)
+		}
+		got, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("reading profile %s: %v", id, err)
+		}
+		if string(got) != want {
+			t.Fatalf("ReadProfile(%s) = %q, want %q", id, got, want)
+		}
+	}
+
+	got, err := st.ListProfiles(ctx, "svc")
+	if err != nil {
+		t.Fatalf("ListProfiles: %v", err)
+	}
+	if len(got) != len(metas) {
+		t.Fatalf("ListProfiles returned %d metas, want %d", len(got), len(metas))
+	}
+	if got[0].ProfileID != "2" || got[1].ProfileID != "1" {
+		t.Fatalf("ListProfiles returned %v, want most-recent-first", got)
+	}
+}
+
+func TestListProfilesNoService(t *testing.T) {
+	st := newTestStorage(t)
+
+	got, err := st.ListProfiles(context.Background(), "does-not-exist")
+	if err != nil {
+		t.Fatalf("ListProfiles: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("ListProfiles returned %d metas, want 0", len(got))
+	}
+}
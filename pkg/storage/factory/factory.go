@@ -0,0 +1,75 @@
+// Package factory implements a registry of storage driver factories,
+// following the pattern used by Docker distribution's
+// registry/storage/driver/factory package. Each storage backend registers
+// itself under a name by calling Register from an init function; main.go
+// then only needs to know the name of the driver the operator selected,
+// not the set of backends that exist.
+package factory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/profefe/profefe/pkg/log"
+	"github.com/profefe/profefe/pkg/storage"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StorageFactory creates storage.ReadWriter instances configured from a
+// generic bag of driver parameters. Each storage driver package implements
+// one and registers it with Register. ctx is profefe's shutdown context:
+// drivers that start background goroutines (e.g. badger's value-log GC)
+// should stop them when ctx is canceled, rather than on their own timer.
+type StorageFactory interface {
+	Create(ctx context.Context, logger *log.Logger, reg prometheus.Registerer, parameters map[string]interface{}) (storage.ReadWriter, error)
+}
+
+var (
+	driversMu sync.Mutex
+	drivers   = make(map[string]StorageFactory)
+)
+
+// Register makes a storage driver factory available under name. It panics
+// if Register is called twice with the same name, or if factory is nil.
+func Register(name string, factory StorageFactory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if factory == nil {
+		panic("storage: Register factory is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("storage: Register called twice for driver " + name)
+	}
+	drivers[name] = factory
+}
+
+// Create returns a storage.ReadWriter built by the driver registered under
+// name, configured with parameters. The caller is expected to blank-import
+// the driver packages it wants available, so they can register themselves.
+func Create(ctx context.Context, name string, logger *log.Logger, reg prometheus.Registerer, parameters map[string]interface{}) (storage.ReadWriter, error) {
+	driversMu.Lock()
+	f, ok := drivers[name]
+	driversMu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("storage: no driver registered under name %q, did you forget to import it?", name)
+	}
+	return f.Create(ctx, logger, reg, parameters)
+}
+
+// Drivers returns the names of the currently registered drivers, sorted
+// alphabetically.
+func Drivers() []string {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
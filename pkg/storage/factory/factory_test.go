@@ -0,0 +1,113 @@
+package factory
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/profefe/profefe/pkg/log"
+	"github.com/profefe/profefe/pkg/storage"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type fakeStorage struct{}
+
+func (fakeStorage) WriteProfile(ctx context.Context, meta storage.Meta, r io.Reader) error {
+	return nil
+}
+
+func (fakeStorage) ReadProfile(ctx context.Context, service string, id storage.ProfileID) (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (fakeStorage) ListProfiles(ctx context.Context, service string) ([]storage.Meta, error) {
+	return nil, nil
+}
+
+type fakeFactory struct{}
+
+func (fakeFactory) Create(ctx context.Context, logger *log.Logger, reg prometheus.Registerer, parameters map[string]interface{}) (storage.ReadWriter, error) {
+	return fakeStorage{}, nil
+}
+
+// withCleanRegistry resets the package-level driver registry after the test,
+// so tests can Register without leaking drivers into other tests.
+func withCleanRegistry(t *testing.T) {
+	t.Helper()
+
+	driversMu.Lock()
+	saved := drivers
+	drivers = make(map[string]StorageFactory)
+	driversMu.Unlock()
+
+	t.Cleanup(func() {
+		driversMu.Lock()
+		drivers = saved
+		driversMu.Unlock()
+	})
+}
+
+func TestRegisterAndCreate(t *testing.T) {
+	withCleanRegistry(t)
+
+	Register("fake", fakeFactory{})
+
+	st, err := Create(context.Background(), "fake", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if _, ok := st.(fakeStorage); !ok {
+		t.Fatalf("Create returned %T, want fakeStorage", st)
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	withCleanRegistry(t)
+
+	Register("fake", fakeFactory{})
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Register did not panic on duplicate name")
+		}
+	}()
+	Register("fake", fakeFactory{})
+}
+
+func TestRegisterNilFactoryPanics(t *testing.T) {
+	withCleanRegistry(t)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Register did not panic on nil factory")
+		}
+	}()
+	Register("fake", nil)
+}
+
+func TestCreateUnknownDriver(t *testing.T) {
+	withCleanRegistry(t)
+
+	if _, err := Create(context.Background(), "does-not-exist", nil, nil, nil); err == nil {
+		t.Fatal("Create with unknown driver name returned no error")
+	}
+}
+
+func TestDriversSorted(t *testing.T) {
+	withCleanRegistry(t)
+
+	Register("zzz", fakeFactory{})
+	Register("aaa", fakeFactory{})
+	Register("mmm", fakeFactory{})
+
+	got := Drivers()
+	want := []string{"aaa", "mmm", "zzz"}
+	if len(got) != len(want) {
+		t.Fatalf("Drivers() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Drivers() = %v, want %v", got, want)
+		}
+	}
+}
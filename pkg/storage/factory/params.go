@@ -0,0 +1,73 @@
+package factory
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Params wraps the generic parameter bag passed to StorageFactory.Create and
+// offers typed accessors, since parameters arriving via repeated
+// --storage.param key=value flags are always strings.
+type Params map[string]interface{}
+
+// String returns the string value for key, or def if key is unset.
+func (p Params) String(key, def string) string {
+	v, ok := p[key]
+	if !ok {
+		return def
+	}
+	return fmt.Sprint(v)
+}
+
+// RequireString returns the string value for key, or an error if it is
+// unset or empty.
+func (p Params) RequireString(key string) (string, error) {
+	s := p.String(key, "")
+	if s == "" {
+		return "", fmt.Errorf("storage: parameter %q is required", key)
+	}
+	return s, nil
+}
+
+// Int64 returns the int64 value for key, or def if key is unset.
+func (p Params) Int64(key string, def int64) (int64, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	return strconv.ParseInt(fmt.Sprint(v), 10, 64)
+}
+
+// Int returns the int value for key, or def if key is unset.
+func (p Params) Int(key string, def int) (int, error) {
+	n, err := p.Int64(key, int64(def))
+	return int(n), err
+}
+
+// Float64 returns the float64 value for key, or def if key is unset.
+func (p Params) Float64(key string, def float64) (float64, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	return strconv.ParseFloat(fmt.Sprint(v), 64)
+}
+
+// Bool returns the bool value for key, or def if key is unset.
+func (p Params) Bool(key string, def bool) (bool, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	return strconv.ParseBool(fmt.Sprint(v))
+}
+
+// Duration returns the time.Duration value for key, or def if key is unset.
+func (p Params) Duration(key string, def time.Duration) (time.Duration, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	return time.ParseDuration(fmt.Sprint(v))
+}
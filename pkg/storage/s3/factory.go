@@ -0,0 +1,95 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/profefe/profefe/pkg/log"
+	"github.com/profefe/profefe/pkg/storage"
+	"github.com/profefe/profefe/pkg/storage/factory"
+	"github.com/profefe/profefe/version"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	factory.Register("s3", &driverFactory{})
+}
+
+type driverFactory struct{}
+
+func (f *driverFactory) Create(ctx context.Context, logger *log.Logger, reg prometheus.Registerer, parameters map[string]interface{}) (storage.ReadWriter, error) {
+	params := factory.Params(parameters)
+
+	bucket, err := params.RequireString("bucket")
+	if err != nil {
+		return nil, err
+	}
+	region := params.String("region", "us-east-1")
+	endpointURL := params.String("endpoint_url", "")
+	disableSSL, err := params.Bool("disable_ssl", false)
+	if err != nil {
+		return nil, err
+	}
+	maxRetries, err := params.Int("max_retries", 3)
+	if err != nil {
+		return nil, err
+	}
+	minRetryDelay, err := params.Duration("retry_min_delay", client.DefaultRetryerMinRetryDelay)
+	if err != nil {
+		return nil, err
+	}
+	maxRetryDelay, err := params.Duration("retry_max_delay", client.DefaultRetryerMaxRetryDelay)
+	if err != nil {
+		return nil, err
+	}
+	uploadPartSize, err := params.Int64("upload_part_size", 5*1024*1024)
+	if err != nil {
+		return nil, err
+	}
+	uploadConcurrency, err := params.Int("upload_concurrency", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	var forcePathStyle bool
+	if endpointURL != "" {
+		// should one use custom object storage service (e.g. Minio), path-style addressing needs to be set
+		forcePathStyle = true
+	}
+
+	retryer := client.DefaultRetryer{
+		NumMaxRetries: maxRetries,
+		MinRetryDelay: minRetryDelay,
+		MaxRetryDelay: maxRetryDelay,
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(endpointURL),
+		DisableSSL:       aws.Bool(disableSSL),
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(forcePathStyle),
+		Retryer:          retryer,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("could not create s3 session: %w", err)
+	}
+
+	// Identify profefe's traffic in bucket access logs, following the pattern
+	// Docker distribution uses for its own S3 driver.
+	sess.Handlers.Build.PushBackNamed(request.NamedHandler{
+		Name: "profefe.UserAgentHandler",
+		Fn:   request.MakeAddToUserAgentFreeFormHandler(fmt.Sprintf("profefe/%s", version.Version)),
+	})
+
+	uploadConf := UploadConfig{
+		PartSize:    uploadPartSize,
+		Concurrency: uploadConcurrency,
+	}
+	return New(s3.New(sess), bucket, uploadConf), nil
+}
@@ -0,0 +1,153 @@
+// Package s3 implements storage.Reader and storage.Writer on top of AWS S3
+// (or any S3-compatible object store).
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/profefe/profefe/pkg/log"
+	"github.com/profefe/profefe/pkg/storage"
+	"golang.org/x/xerrors"
+)
+
+// Storage is a storage.Reader/storage.Writer backed by S3.
+type Storage struct {
+	s3         s3iface.S3API
+	bucket     string
+	uploader   *s3manager.Uploader
+	downloader *s3manager.Downloader
+}
+
+// UploadConfig configures the multipart uploader and streaming downloader
+// used by Storage.
+type UploadConfig struct {
+	// PartSize is the size, in bytes, of each part of a multipart upload or
+	// ranged download.
+	PartSize int64
+	// Concurrency is the number of parts uploaded in parallel. It does not
+	// apply to downloads: streaming reads always use a concurrency of 1, so
+	// that ranged parts arrive, and are written to the returned io.Reader,
+	// in order.
+	Concurrency int
+}
+
+// New returns a new Storage that stores profiles in bucket.
+func New(svc s3iface.S3API, bucket string, uploadConf UploadConfig) *Storage {
+	return &Storage{
+		s3:     svc,
+		bucket: bucket,
+		uploader: s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+			u.PartSize = uploadConf.PartSize
+			u.Concurrency = uploadConf.Concurrency
+			u.LeavePartsOnError = false
+		}),
+		downloader: s3manager.NewDownloaderWithClient(svc, func(d *s3manager.Downloader) {
+			d.PartSize = uploadConf.PartSize
+			d.Concurrency = 1
+		}),
+	}
+}
+
+func (st *Storage) WriteProfile(ctx context.Context, meta storage.Meta, r io.Reader) error {
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return xerrors.Errorf("could not marshal profile metadata: %w", err)
+	}
+
+	_, err = st.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:   aws.String(st.bucket),
+		Key:      aws.String(meta.Key()),
+		Body:     r,
+		Metadata: map[string]*string{"profefe-meta": aws.String(string(metaJSON))},
+	})
+	if err != nil {
+		return xerrors.Errorf("could not upload object %s: %w", meta.Key(), err)
+	}
+
+	return nil
+}
+
+// rangedWriterAt adapts an io.PipeWriter, which the downloader is only
+// allowed to write to sequentially, to the io.WriterAt the downloader
+// requires. Storage.downloader is configured with Concurrency 1, so parts
+// always arrive in offset order.
+type rangedWriterAt struct {
+	w      *io.PipeWriter
+	offset int64
+}
+
+func (rw *rangedWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if off != rw.offset {
+		return 0, fmt.Errorf("s3: out-of-order ranged write at offset %d, expected %d", off, rw.offset)
+	}
+	n, err := rw.w.Write(p)
+	rw.offset += int64(n)
+	return n, err
+}
+
+func (st *Storage) ReadProfile(ctx context.Context, service string, id storage.ProfileID) (io.ReadCloser, error) {
+	key := service + "/" + string(id)
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := st.downloader.DownloadWithContext(ctx, &rangedWriterAt{w: pw}, &s3.GetObjectInput{
+			Bucket: aws.String(st.bucket),
+			Key:    aws.String(key),
+		})
+		pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+func (st *Storage) ListProfiles(ctx context.Context, service string) ([]storage.Meta, error) {
+	logger := log.LoggerFromContext(ctx)
+
+	var metas []storage.Meta
+
+	err := st.s3.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(st.bucket),
+		Prefix: aws.String(service + "/"),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			head, err := st.s3.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+				Bucket: aws.String(st.bucket),
+				Key:    obj.Key,
+			})
+			if err != nil {
+				logger.Error("could not head object", "key", aws.StringValue(obj.Key), "error", err)
+				continue
+			}
+
+			rawMeta, ok := head.Metadata["Profefe-Meta"]
+			if !ok || rawMeta == nil {
+				continue
+			}
+
+			var meta storage.Meta
+			if err := json.Unmarshal([]byte(*rawMeta), &meta); err != nil {
+				logger.Error("could not unmarshal profile metadata", "key", aws.StringValue(obj.Key), "error", err)
+				continue
+			}
+			metas = append(metas, meta)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("could not list objects for service %s: %w", service, err)
+	}
+
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].CreatedAt.After(metas[j].CreatedAt)
+	})
+
+	return metas, nil
+}
@@ -0,0 +1,334 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/profefe/profefe/pkg/storage"
+)
+
+// fakeMultipartUpload tracks the parts of an in-progress multipart upload
+// started against a fakeS3.
+type fakeMultipartUpload struct {
+	bucket, key string
+	metadata    map[string]*string
+	parts       map[int64][]byte
+}
+
+// fakeS3 is a minimal s3iface.S3API backed by an in-memory map. It implements
+// just enough of PutObjectRequest, the multipart upload calls
+// (Create/Upload/Complete/AbortMultipartUpload), GetObjectRequest,
+// GetObjectWithContext, HeadObjectWithContext, and
+// ListObjectsV2PagesWithContext to drive s3manager's uploader/downloader for
+// both single-part and multipart payloads, so Storage can be exercised
+// without talking to real S3.
+type fakeS3 struct {
+	s3iface.S3API
+
+	mu        sync.Mutex
+	objects   map[string][]byte
+	metadata  map[string]map[string]*string
+	multipart map[string]*fakeMultipartUpload
+	uploadSeq int64
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{
+		objects:   make(map[string][]byte),
+		metadata:  make(map[string]map[string]*string),
+		multipart: make(map[string]*fakeMultipartUpload),
+	}
+}
+
+func objectKey(bucket, key string) string {
+	return bucket + "/" + key
+}
+
+// canonicalMetaKey mirrors the header canonicalization the real S3 API
+// applies to user metadata round-tripped through a request, e.g.
+// "profefe-meta" comes back as "Profefe-Meta", which is the form
+// Storage.ListProfiles looks it up by.
+func canonicalMetaKey(key string) string {
+	parts := strings.Split(key, "-")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func (f *fakeS3) PutObjectRequest(in *s3.PutObjectInput) (*request.Request, *s3.PutObjectOutput) {
+	out := &s3.PutObjectOutput{}
+	op := &request.Operation{Name: "PutObject", HTTPMethod: "PUT"}
+	req := request.New(aws.Config{}, metadata.ClientInfo{}, request.Handlers{}, nil, op, in, out)
+
+	req.Handlers.Send.PushBack(func(r *request.Request) {
+		body, err := ioutil.ReadAll(in.Body)
+		if err != nil {
+			r.Error = err
+			return
+		}
+
+		meta := make(map[string]*string, len(in.Metadata))
+		for k, v := range in.Metadata {
+			meta[canonicalMetaKey(k)] = v
+		}
+
+		key := objectKey(aws.StringValue(in.Bucket), aws.StringValue(in.Key))
+
+		f.mu.Lock()
+		f.objects[key] = body
+		f.metadata[key] = meta
+		f.mu.Unlock()
+	})
+
+	return req, out
+}
+
+func (f *fakeS3) CreateMultipartUploadWithContext(ctx aws.Context, in *s3.CreateMultipartUploadInput, opts ...request.Option) (*s3.CreateMultipartUploadOutput, error) {
+	meta := make(map[string]*string, len(in.Metadata))
+	for k, v := range in.Metadata {
+		meta[canonicalMetaKey(k)] = v
+	}
+
+	f.mu.Lock()
+	f.uploadSeq++
+	uploadID := strconv.FormatInt(f.uploadSeq, 10)
+	f.multipart[uploadID] = &fakeMultipartUpload{
+		bucket:   aws.StringValue(in.Bucket),
+		key:      aws.StringValue(in.Key),
+		metadata: meta,
+		parts:    make(map[int64][]byte),
+	}
+	f.mu.Unlock()
+
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String(uploadID)}, nil
+}
+
+func (f *fakeS3) UploadPartWithContext(ctx aws.Context, in *s3.UploadPartInput, opts ...request.Option) (*s3.UploadPartOutput, error) {
+	body, err := ioutil.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	up, ok := f.multipart[aws.StringValue(in.UploadId)]
+	if ok {
+		up.parts[aws.Int64Value(in.PartNumber)] = body
+	}
+	f.mu.Unlock()
+	if !ok {
+		return nil, awserr.New("NoSuchUpload", "no such upload", nil)
+	}
+
+	return &s3.UploadPartOutput{ETag: aws.String(strconv.FormatInt(aws.Int64Value(in.PartNumber), 10))}, nil
+}
+
+func (f *fakeS3) CompleteMultipartUploadWithContext(ctx aws.Context, in *s3.CompleteMultipartUploadInput, opts ...request.Option) (*s3.CompleteMultipartUploadOutput, error) {
+	f.mu.Lock()
+	up, ok := f.multipart[aws.StringValue(in.UploadId)]
+	if ok {
+		delete(f.multipart, aws.StringValue(in.UploadId))
+	}
+	f.mu.Unlock()
+	if !ok {
+		return nil, awserr.New("NoSuchUpload", "no such upload", nil)
+	}
+
+	nums := make([]int64, 0, len(up.parts))
+	for num := range up.parts {
+		nums = append(nums, num)
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+
+	var body []byte
+	for _, num := range nums {
+		body = append(body, up.parts[num]...)
+	}
+
+	key := objectKey(up.bucket, up.key)
+
+	f.mu.Lock()
+	f.objects[key] = body
+	f.metadata[key] = up.metadata
+	f.mu.Unlock()
+
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (f *fakeS3) AbortMultipartUploadWithContext(ctx aws.Context, in *s3.AbortMultipartUploadInput, opts ...request.Option) (*s3.AbortMultipartUploadOutput, error) {
+	f.mu.Lock()
+	delete(f.multipart, aws.StringValue(in.UploadId))
+	f.mu.Unlock()
+
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+// GetObjectRequest backs the presigned-URL lookup s3manager's multipart
+// uploader makes once a multipart upload completes. Storage never uses the
+// returned URL, so a request that signs without error is enough.
+func (f *fakeS3) GetObjectRequest(in *s3.GetObjectInput) (*request.Request, *s3.GetObjectOutput) {
+	out := &s3.GetObjectOutput{}
+	op := &request.Operation{Name: "GetObject", HTTPMethod: "GET"}
+	req := request.New(aws.Config{}, metadata.ClientInfo{}, request.Handlers{}, nil, op, in, out)
+	return req, out
+}
+
+func (f *fakeS3) GetObjectWithContext(ctx aws.Context, in *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	key := objectKey(aws.StringValue(in.Bucket), aws.StringValue(in.Key))
+
+	f.mu.Lock()
+	data, ok := f.objects[key]
+	f.mu.Unlock()
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "no such key", nil)
+	}
+
+	start, end := int64(0), int64(len(data))-1
+	if rng := aws.StringValue(in.Range); rng != "" {
+		bounds := strings.SplitN(strings.TrimPrefix(rng, "bytes="), "-", 2)
+		start, _ = strconv.ParseInt(bounds[0], 10, 64)
+		if len(bounds) == 2 && bounds[1] != "" {
+			if e, err := strconv.ParseInt(bounds[1], 10, 64); err == nil && e < end {
+				end = e
+			}
+		}
+	}
+	if start > int64(len(data)) {
+		start = int64(len(data))
+	}
+	if end >= int64(len(data)) {
+		end = int64(len(data)) - 1
+	}
+	chunk := data[start : end+1]
+
+	return &s3.GetObjectOutput{
+		Body:          ioutil.NopCloser(bytes.NewReader(chunk)),
+		ContentLength: aws.Int64(int64(len(chunk))),
+		ContentRange:  aws.String(fmt.Sprintf("bytes %d-%d/%d", start, end, len(data))),
+	}, nil
+}
+
+func (f *fakeS3) HeadObjectWithContext(ctx aws.Context, in *s3.HeadObjectInput, opts ...request.Option) (*s3.HeadObjectOutput, error) {
+	key := objectKey(aws.StringValue(in.Bucket), aws.StringValue(in.Key))
+
+	f.mu.Lock()
+	meta, ok := f.metadata[key]
+	f.mu.Unlock()
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "no such key", nil)
+	}
+
+	return &s3.HeadObjectOutput{Metadata: meta}, nil
+}
+
+func (f *fakeS3) ListObjectsV2PagesWithContext(ctx aws.Context, in *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool, opts ...request.Option) error {
+	bucketPrefix := aws.StringValue(in.Bucket) + "/"
+	prefix := aws.StringValue(in.Prefix)
+
+	f.mu.Lock()
+	var objs []*s3.Object
+	for key := range f.objects {
+		k := strings.TrimPrefix(key, bucketPrefix)
+		if k != key && strings.HasPrefix(k, prefix) {
+			objs = append(objs, &s3.Object{Key: aws.String(k)})
+		}
+	}
+	f.mu.Unlock()
+
+	fn(&s3.ListObjectsV2Output{Contents: objs}, true)
+	return nil
+}
+
+func newTestStorage() *Storage {
+	return New(newFakeS3(), "test-bucket", UploadConfig{PartSize: 5 * 1024 * 1024, Concurrency: 1})
+}
+
+func TestWriteReadListProfiles(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+
+	metas := []storage.Meta{
+		{ProfileID: "1", Service: "svc", Type: "cpu", CreatedAt: time.Now().Add(-time.Minute)},
+		{ProfileID: "2", Service: "svc", Type: "cpu", CreatedAt: time.Now()},
+	}
+	payloads := map[storage.ProfileID]string{
+		"1": "first profile payload",
+		"2": "second profile payload",
+	}
+
+	for _, m := range metas {
+		if err := st.WriteProfile(ctx, m, strings.NewReader(payloads[m.ProfileID])); err != nil {
+			t.Fatalf("WriteProfile(%s): %v", m.ProfileID, err)
+		}
+	}
+
+	for id, want := range payloads {
+		rc, err := st.ReadProfile(ctx, "svc", id)
+		if err != nil {
+			t.Fatalf("ReadProfile(%s): %v", id, err)
+		}
+		got, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("reading profile %s: %v", id, err)
+		}
+		if string(got) != want {
+			t.Fatalf("ReadProfile(%s) = %q, want %q", id, got, want)
+		}
+	}
+
+	got, err := st.ListProfiles(ctx, "svc")
+	if err != nil {
+		t.Fatalf("ListProfiles: %v", err)
+	}
+	if len(got) != len(metas) {
+		t.Fatalf("ListProfiles returned %d metas, want %d", len(got), len(metas))
+	}
+	if got[0].ProfileID != "2" || got[1].ProfileID != "1" {
+		t.Fatalf("ListProfiles returned %v, want most-recent-first", got)
+	}
+}
+
+// TestWriteReadLargeProfile uploads a payload bigger than PartSize, forcing
+// the uploader/downloader down their multipart/ranged-chunked paths instead
+// of the single PutObject/GetObject path TestWriteReadListProfiles exercises.
+func TestWriteReadLargeProfile(t *testing.T) {
+	st := newTestStorage()
+	ctx := context.Background()
+
+	payload := bytes.Repeat([]byte("0123456789abcdef"), (12<<20)/16+1) // > 12MiB, > 2x PartSize
+	meta := storage.Meta{ProfileID: "large", Service: "svc", Type: "cpu", CreatedAt: time.Now()}
+
+	if err := st.WriteProfile(ctx, meta, bytes.NewReader(payload)); err != nil {
+		t.Fatalf("WriteProfile: %v", err)
+	}
+
+	rc, err := st.ReadProfile(ctx, "svc", meta.ProfileID)
+	if err != nil {
+		t.Fatalf("ReadProfile: %v", err)
+	}
+	got, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("reading profile: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("ReadProfile returned %d bytes, want %d bytes matching the payload", len(got), len(payload))
+	}
+}
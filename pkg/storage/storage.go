@@ -0,0 +1,52 @@
+// Package storage defines the interfaces profefe uses to persist and serve
+// collected profiling data. Concrete backends (badger, s3, blob, ...) live in
+// their own sub-packages and implement Reader and/or Writer.
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// ProfileID identifies a single stored profile.
+type ProfileID string
+
+// Meta describes a profile without its payload.
+type Meta struct {
+	ProfileID ProfileID
+	Service   string
+	Type      string
+	CreatedAt time.Time
+}
+
+// Key returns the storage key under which the profile's payload is stored.
+// Backends that address data by flat key (S3, gocloud blob, badger) use this
+// as the object/row key.
+func (m Meta) Key() string {
+	return m.Service + "/" + string(m.ProfileID)
+}
+
+// Writer persists a profile's payload together with its metadata.
+type Writer interface {
+	WriteProfile(ctx context.Context, meta Meta, r io.Reader) error
+}
+
+// Reader retrieves previously stored profiles.
+type Reader interface {
+	// ReadProfile returns the payload for the profile with the given service
+	// and ID. Callers must supply service, not just id, so backends can
+	// address the profile's key directly instead of scanning for it.
+	ReadProfile(ctx context.Context, service string, id ProfileID) (io.ReadCloser, error)
+
+	// ListProfiles returns metadata for every profile stored for service,
+	// most recent first.
+	ListProfiles(ctx context.Context, service string) ([]Meta, error)
+}
+
+// ReadWriter is a storage backend that can both persist and serve profiles.
+// It is the type storage driver factories hand back to callers.
+type ReadWriter interface {
+	Reader
+	Writer
+}
@@ -0,0 +1,20 @@
+// Package version holds build-time metadata, set via -ldflags at compile time.
+package version
+
+import "fmt"
+
+var (
+	// Version is the semantic version of the build, overridden at link time.
+	Version = "dev"
+
+	// Commit is the git commit hash of the build, overridden at link time.
+	Commit = "none"
+
+	// BuildDate is the date the binary was built, overridden at link time.
+	BuildDate = "unknown"
+)
+
+// String returns a human-readable representation of the build metadata.
+func String() string {
+	return fmt.Sprintf("profefe %s (commit: %s, built: %s)", Version, Commit, BuildDate)
+}